@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// formatRate renders a float64 rate as a plain decimal string (never
+// scientific notation), since ParseDecimal can't parse exponents and many
+// altcoin rates fall in the range Go's default %v formatting switches to
+// "1e-05"-style output for.
+func formatRate(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Quote is a MarketTicker converted into a target fiat/crypto currency,
+// mirroring the shape CoinMarketCap's API uses for its per-currency quotes.
+type Quote struct {
+	Last   Decimal `json:"last"`
+	Bid    Decimal `json:"bid"`
+	Ask    Decimal `json:"ask"`
+	Volume Decimal `json:"volume"`
+}
+
+// RateSource fetches conversion rates for one unit of the configured base
+// currency into every supported target currency (e.g. rates["EUR"] is how
+// many EUR one unit of the base currency is worth).
+type RateSource interface {
+	Name() string
+	FetchRates(ctx context.Context) (map[string]Decimal, error)
+}
+
+// StaticRateSource serves a fixed rate table from config, for deployments
+// that don't want to depend on an external rates API.
+type StaticRateSource struct {
+	rates map[string]Decimal
+}
+
+// NewStaticRateSource builds a StaticRateSource from a config-provided
+// currency -> rate map.
+func NewStaticRateSource(rates map[string]float64) *StaticRateSource {
+	parsed := make(map[string]Decimal, len(rates))
+	for currency, rate := range rates {
+		currency = strings.ToUpper(currency)
+		d, err := ParseDecimal(formatRate(rate))
+		if err != nil {
+			log.Printf("static rate source: skipping %s: %v\n", currency, err)
+			continue
+		}
+		parsed[currency] = d
+	}
+	return &StaticRateSource{rates: parsed}
+}
+
+func (s *StaticRateSource) Name() string { return "static" }
+
+func (s *StaticRateSource) FetchRates(ctx context.Context) (map[string]Decimal, error) {
+	return s.rates, nil
+}
+
+// CoinGeckoRateSource fetches rates from the public CoinGecko API.
+type CoinGeckoRateSource struct {
+	baseCoinID string
+	currencies []string
+}
+
+// NewCoinGeckoRateSource builds a rate source for baseCoinID (a CoinGecko coin
+// id, e.g. "bitcoin") quoted in the given vs-currencies (e.g. "usd", "eur").
+func NewCoinGeckoRateSource(baseCoinID string, currencies []string) *CoinGeckoRateSource {
+	return &CoinGeckoRateSource{baseCoinID: baseCoinID, currencies: currencies}
+}
+
+func (s *CoinGeckoRateSource) Name() string { return "coingecko" }
+
+func (s *CoinGeckoRateSource) FetchRates(ctx context.Context) (map[string]Decimal, error) {
+	url := fmt.Sprintf("https://api.coingecko.com/api/v3/simple/price?ids=%s&vs_currencies=%s",
+		s.baseCoinID, strings.ToLower(strings.Join(s.currencies, ",")))
+
+	var resp map[string]map[string]float64
+	if err := httpGetJSON(ctx, url, &resp); err != nil {
+		return nil, err
+	}
+
+	prices, ok := resp[s.baseCoinID]
+	if !ok {
+		return nil, fmt.Errorf("coingecko: no data for %s", s.baseCoinID)
+	}
+	rates := make(map[string]Decimal, len(prices))
+	for currency, price := range prices {
+		rate, err := ParseDecimal(formatRate(price))
+		if err != nil {
+			log.Printf("coingecko: skipping %s: %v\n", currency, err)
+			continue
+		}
+		rates[strings.ToUpper(currency)] = rate
+	}
+	return rates, nil
+}
+
+// CMCRateSource fetches rates from the CoinMarketCap Pro API, authenticated
+// via the CMC_PRO_API_KEY environment variable.
+type CMCRateSource struct {
+	baseSymbol string
+	currencies []string
+	apiKey     string
+}
+
+// NewCMCRateSource builds a rate source for baseSymbol (e.g. "BTC") quoted in
+// the given target currencies, reading its API key from CMC_PRO_API_KEY.
+func NewCMCRateSource(baseSymbol string, currencies []string) *CMCRateSource {
+	return &CMCRateSource{baseSymbol: baseSymbol, currencies: currencies, apiKey: os.Getenv("CMC_PRO_API_KEY")}
+}
+
+func (s *CMCRateSource) Name() string { return "coinmarketcap" }
+
+func (s *CMCRateSource) FetchRates(ctx context.Context) (map[string]Decimal, error) {
+	if s.apiKey == "" {
+		return nil, fmt.Errorf("coinmarketcap: CMC_PRO_API_KEY is not set")
+	}
+
+	url := fmt.Sprintf("https://pro-api.coinmarketcap.com/v2/tools/price-conversion?amount=1&symbol=%s&convert=%s",
+		s.baseSymbol, strings.Join(s.currencies, ","))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-CMC_PRO_API_KEY", s.apiKey)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Data struct {
+			Quote map[string]struct {
+				Price float64 `json:"price"`
+			} `json:"quote"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	rates := make(map[string]Decimal, len(body.Data.Quote))
+	for currency, q := range body.Data.Quote {
+		rate, err := ParseDecimal(formatRate(q.Price))
+		if err != nil {
+			log.Printf("coinmarketcap: skipping %s: %v\n", currency, err)
+			continue
+		}
+		rates[strings.ToUpper(currency)] = rate
+	}
+	return rates, nil
+}
+
+// ConversionConfig configures the fiat/crypto conversion layer.
+type ConversionConfig struct {
+	// Source selects the rate provider: "cmc", "coingecko", or "static".
+	Source string `json:"source"`
+	// BaseCurrency is the currency tickers are denominated in (e.g. "USD").
+	BaseCurrency string `json:"baseCurrency"`
+	// Currencies lists the target currencies to fetch rates for.
+	Currencies []string `json:"currencies"`
+	// StaticRates backs the "static" source: currency -> rate per unit of BaseCurrency.
+	StaticRates map[string]float64 `json:"staticRates"`
+	// CacheTTL bounds how long fetched rates are reused before refreshing.
+	CacheTTL time.Duration `json:"cacheTTL"`
+}
+
+// newRateSource constructs the RateSource selected by cfg.
+func newRateSource(cfg ConversionConfig) (RateSource, error) {
+	switch strings.ToLower(cfg.Source) {
+	case "", "static":
+		return NewStaticRateSource(cfg.StaticRates), nil
+	case "coingecko":
+		return NewCoinGeckoRateSource(strings.ToLower(cfg.BaseCurrency), cfg.Currencies), nil
+	case "cmc", "coinmarketcap":
+		return NewCMCRateSource(cfg.BaseCurrency, cfg.Currencies), nil
+	default:
+		return nil, fmt.Errorf("unknown conversion source: %s", cfg.Source)
+	}
+}
+
+// ConversionService caches rates fetched from a RateSource and converts
+// MarketTicker values into Quotes for a set of target currencies.
+type ConversionService struct {
+	source RateSource
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	rates     map[string]Decimal
+	fetchedAt time.Time
+}
+
+// NewConversionService builds a ConversionService for cfg.
+func NewConversionService(cfg ConversionConfig) (*ConversionService, error) {
+	source, err := newRateSource(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ttl := cfg.CacheTTL
+	if ttl == 0 {
+		ttl = time.Minute
+	}
+	return &ConversionService{source: source, ttl: ttl}, nil
+}
+
+// Rates returns the current rate table, refreshing it from the source if the
+// cached copy has expired.
+func (c *ConversionService) Rates(ctx context.Context) (map[string]Decimal, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.rates != nil && time.Since(c.fetchedAt) < c.ttl {
+		return c.rates, nil
+	}
+
+	rates, err := c.source.FetchRates(ctx)
+	if err != nil {
+		if c.rates != nil {
+			// Serve the stale cache rather than fail the request outright.
+			return c.rates, nil
+		}
+		return nil, err
+	}
+	c.rates = rates
+	c.fetchedAt = time.Now()
+	return rates, nil
+}
+
+// Quote converts ticker into a Quote for every currency in targets.
+func (c *ConversionService) Quote(ctx context.Context, ticker MarketTicker, targets []string) (map[string]Quote, error) {
+	rates, err := c.Rates(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := make(map[string]Quote, len(targets))
+	for _, target := range targets {
+		target = strings.ToUpper(strings.TrimSpace(target))
+		rate, ok := rates[target]
+		if !ok {
+			continue
+		}
+		quotes[target] = Quote{
+			Last:   ticker.Last.Mul(rate),
+			Bid:    ticker.Bid.Mul(rate),
+			Ask:    ticker.Ask.Mul(rate),
+			Volume: ticker.Volume.Mul(rate),
+		}
+	}
+	return quotes, nil
+}
+
+// RatesHandler handles GET /rates, returning the current conversion rate
+// table for visibility into what the service is using.
+func RatesHandler(service *ConversionService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rates, err := service.Rates(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		js, err := json.Marshal(rates)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(js)
+	}
+}
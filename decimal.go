@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// decimalScale is the number of implied decimal places stored in a Decimal's
+// underlying int64, similar in spirit to bbgo's pkg/fixedpoint.Value.
+const decimalScale = 8
+
+var decimalMultiplier int64 = 1
+
+func init() {
+	for i := 0; i < decimalScale; i++ {
+		decimalMultiplier *= 10
+	}
+}
+
+// Decimal is a fixed-point decimal value. It parses from a JSON string once on
+// ingest (rather than repeatedly re-parsing a raw string), and serializes back
+// to a string so the wire format is unchanged.
+type Decimal int64
+
+// ParseDecimal parses s (e.g. "1234.5678") into a Decimal. An empty string
+// parses to the zero value, matching upstream APIs that omit a field rather
+// than send "0".
+func ParseDecimal(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+
+	neg := false
+	if strings.HasPrefix(s, "-") {
+		neg = true
+		s = s[1:]
+	}
+
+	intPart, fracPart, hasFrac := strings.Cut(s, ".")
+	if hasFrac && strings.Contains(fracPart, ".") {
+		return 0, fmt.Errorf("fixedpoint: invalid decimal %q", s)
+	}
+	if intPart == "" && fracPart == "" {
+		return 0, fmt.Errorf("fixedpoint: invalid decimal %q", s)
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+
+	whole, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("fixedpoint: invalid decimal %q: %w", s, err)
+	}
+
+	for len(fracPart) < decimalScale {
+		fracPart += "0"
+	}
+	fracPart = fracPart[:decimalScale]
+
+	var frac int64
+	if fracPart != "" {
+		frac, err = strconv.ParseInt(fracPart, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("fixedpoint: invalid decimal %q: %w", s, err)
+		}
+	}
+
+	value := whole*decimalMultiplier + frac
+	if neg {
+		value = -value
+	}
+	return Decimal(value), nil
+}
+
+// String renders the Decimal back to its canonical string form.
+func (d Decimal) String() string {
+	neg := d < 0
+	v := int64(d)
+	if neg {
+		v = -v
+	}
+	whole := v / decimalMultiplier
+	frac := v % decimalMultiplier
+
+	s := strconv.FormatInt(whole, 10)
+	fracStr := strings.TrimRight(fmt.Sprintf("%0*d", decimalScale, frac), "0")
+	if fracStr != "" {
+		s += "." + fracStr
+	}
+	if neg {
+		s = "-" + s
+	}
+	return s
+}
+
+// MarshalJSON serializes the Decimal as a JSON string, matching the wire
+// format of the plain-string fields it replaces.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON parses a JSON string (or number, for leniency) into the Decimal.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		// Fall back to a bare JSON number, e.g. {"last": 123.45}.
+		var raw json.Number
+		if err2 := json.Unmarshal(data, &raw); err2 != nil {
+			return err
+		}
+		s = raw.String()
+	}
+	v, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// IsZero reports whether the Decimal is exactly zero.
+func (d Decimal) IsZero() bool { return d == 0 }
+
+// Sub returns d - other.
+func (d Decimal) Sub(other Decimal) Decimal { return d - other }
+
+// Div returns d / other as a Decimal, or an error if other is zero. The
+// intermediate product is computed with math/big since scaling d up by
+// decimalMultiplier before dividing overflows int64 for perfectly ordinary
+// price magnitudes.
+func (d Decimal) Div(other Decimal) (Decimal, error) {
+	if other.IsZero() {
+		return 0, fmt.Errorf("fixedpoint: division by zero")
+	}
+	num := new(big.Int).Mul(big.NewInt(int64(d)), big.NewInt(decimalMultiplier))
+	num.Quo(num, big.NewInt(int64(other)))
+	return Decimal(num.Int64()), nil
+}
+
+// Mul returns d * other. The intermediate product is computed with math/big
+// since d * other overflows int64 before it's scaled back down.
+func (d Decimal) Mul(other Decimal) Decimal {
+	prod := new(big.Int).Mul(big.NewInt(int64(d)), big.NewInt(int64(other)))
+	prod.Quo(prod, big.NewInt(decimalMultiplier))
+	return Decimal(prod.Int64())
+}
@@ -0,0 +1,86 @@
+package main
+
+import "testing"
+
+func TestParseDecimal(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "123.456", want: "123.456"},
+		{in: "-10.5", want: "-10.5"},
+		{in: "0", want: "0"},
+		{in: "", want: "0"},
+		{in: "not-a-number", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+	}
+
+	for _, c := range cases {
+		got, err := ParseDecimal(c.in)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("ParseDecimal(%q): expected error, got %v", c.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDecimal(%q): unexpected error: %v", c.in, err)
+			continue
+		}
+		if got.String() != c.want {
+			t.Errorf("ParseDecimal(%q).String() = %q, want %q", c.in, got.String(), c.want)
+		}
+	}
+}
+
+func TestDecimalDivByZero(t *testing.T) {
+	a, _ := ParseDecimal("10")
+	zero, _ := ParseDecimal("0")
+	if _, err := a.Div(zero); err == nil {
+		t.Errorf("Div by zero: expected error, got nil")
+	}
+}
+
+func TestChangeAndPercent(t *testing.T) {
+	last, _ := ParseDecimal("110")
+	open, _ := ParseDecimal("100")
+	change, percent := changeAndPercent(last, open)
+	if change.String() != "10" {
+		t.Errorf("change = %s, want 10", change.String())
+	}
+	if percent.String() != "0.1" {
+		t.Errorf("percent = %s, want 0.1", percent.String())
+	}
+
+	zero, _ := ParseDecimal("0")
+	change, percent = changeAndPercent(last, zero)
+	if change.String() != "110" {
+		t.Errorf("change vs zero open = %s, want 110", change.String())
+	}
+	if !percent.IsZero() {
+		t.Errorf("percent vs zero open = %s, want 0", percent.String())
+	}
+}
+
+func TestChangeAndPercentRealisticMagnitude(t *testing.T) {
+	// A completely ordinary BTCUSD tick. decimalMultiplier scaling must not
+	// overflow int64 for prices in this range.
+	open, _ := ParseDecimal("65000")
+	last, _ := ParseDecimal("64000")
+	change, percent := changeAndPercent(last, open)
+	if change.String() != "-1000" {
+		t.Errorf("change = %s, want -1000", change.String())
+	}
+	if got := percent.String(); got != "-0.01538461" {
+		t.Errorf("percent = %s, want -0.01538461", got)
+	}
+}
+
+func TestMulRealisticMagnitude(t *testing.T) {
+	price, _ := ParseDecimal("65000")
+	rate, _ := ParseDecimal("0.92")
+	if got := price.Mul(rate).String(); got != "59800" {
+		t.Errorf("65000 * 0.92 = %s, want 59800", got)
+	}
+}
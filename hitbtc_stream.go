@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const hitbtcWSEndpoint = "wss://api.hitbtc.com/api/2/ws/public"
+
+// hitbtcSubscribeRequest is the JSON-RPC request HitBTC expects to subscribe
+// to ticker updates for a single symbol.
+type hitbtcSubscribeRequest struct {
+	Method string            `json:"method"`
+	Params map[string]string `json:"params"`
+	ID     int               `json:"id"`
+}
+
+// hitbtcTickerNotification is the JSON-RPC notification HitBTC pushes on
+// every ticker update.
+type hitbtcTickerNotification struct {
+	Method string `json:"method"`
+	Params struct {
+		Ask    string `json:"ask"`
+		Bid    string `json:"bid"`
+		Last   string `json:"last"`
+		Open   string `json:"open"`
+		Low    string `json:"low"`
+		High   string `json:"high"`
+		Volume string `json:"volume"`
+		Symbol string `json:"symbol"`
+	} `json:"params"`
+}
+
+// HitBTCStream keeps markets up to date in real time from HitBTC's public
+// websocket feed, in place of the 10s REST poll. It supervises the
+// connection, reconnecting with exponential backoff and jitter and
+// re-subscribing to every configured symbol after each reconnect.
+type HitBTCStream struct {
+	symbols []string
+	markets *Markets
+	hub     *tickerHub
+	store   TickStore
+	tracker *freshnessTracker
+}
+
+// NewHitBTCStream constructs a stream that updates markets (namespaced under
+// "hitbtc:"), publishes every change to hub, and records it to store.
+func NewHitBTCStream(symbols []string, markets *Markets, hub *tickerHub, store TickStore, tracker *freshnessTracker) *HitBTCStream {
+	return &HitBTCStream{symbols: symbols, markets: markets, hub: hub, store: store, tracker: tracker}
+}
+
+// Run connects and reconnects until ctx is cancelled.
+func (s *HitBTCStream) Run(ctx context.Context) {
+	backoff := time.Second
+	const maxBackoff = 30 * time.Second
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		err := s.runOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("hitbtc stream disconnected: %v; reconnecting in %s\n", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(jitter(backoff)):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// jitter returns d plus up to 50% random jitter, so a reconnect storm doesn't
+// hit HitBTC in lockstep.
+func jitter(d time.Duration) time.Duration {
+	return d + time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
+// runOnce holds a single connection open until it errors out or ctx is
+// cancelled, resetting the caller's backoff on a clean connect.
+func (s *HitBTCStream) runOnce(ctx context.Context) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, hitbtcWSEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for i, symbol := range s.symbols {
+		req := hitbtcSubscribeRequest{
+			Method: "subscribeTicker",
+			Params: map[string]string{"symbol": symbol},
+			ID:     i,
+		}
+		if err := conn.WriteJSON(req); err != nil {
+			return err
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+		close(done)
+	}()
+
+	const pongWait = 60 * time.Second
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+		return nil
+	})
+
+	pingTicker := time.NewTicker(pongWait / 2)
+	defer pingTicker.Stop()
+	go func() {
+		for range pingTicker.C {
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-done:
+				return nil
+			default:
+				return err
+			}
+		}
+		s.handleMessage(message)
+	}
+}
+
+// handleMessage parses a single websocket frame and, if it's a ticker
+// notification, updates markets and publishes the change.
+func (s *HitBTCStream) handleMessage(message []byte) {
+	var notification hitbtcTickerNotification
+	if err := json.Unmarshal(message, &notification); err != nil {
+		return
+	}
+	if notification.Method != "ticker" || notification.Params.Symbol == "" {
+		return
+	}
+
+	p := notification.Params
+	ticker, err := buildTicker(p.Ask, p.Bid, p.Last, p.Open, p.Low, p.High, p.Volume)
+	if err != nil {
+		log.Printf("hitbtc: skipping malformed ticker for %s: %v\n", p.Symbol, err)
+		return
+	}
+
+	key := marketKey("hitbtc", p.Symbol)
+	s.markets.Set(key, ticker)
+	s.hub.Updates <- Update{Key: key, Ticker: ticker}
+
+	now := time.Now()
+	if err := s.store.RecordTick(context.Background(), key, ticker, now); err != nil {
+		log.Printf("hitbtc: error recording tick for %s: %v\n", key, err)
+	}
+	s.tracker.touch(key, now)
+}
@@ -1,29 +1,36 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 )
 
+// pollInterval is how often REST-polled providers are refreshed.
+const pollInterval = 10 * time.Second
+
 // Currency struct represents the data for a single currency
 type Currency struct {
-	ID           string `json:"id"`
-	FullName     string `json:"fullName"`
-	Ask          string `json:"ask"`
-	Bid          string `json:"bid"`
-	Last         string `json:"last"`
-	Open         string `json:"open"`
-	Low          string `json:"low"`
-	High         string `json:"high"`
-	FeeCurrency  string `json:"feeCurrency"`
-	Volume       string `json:"volume"`
-	QuoteVolume  string `json:"quoteVolume"`
-	Change       string `json:"change"`
-	PercentChage string `json:"percentChange"`
+	ID           string           `json:"id"`
+	FullName     string           `json:"fullName"`
+	Ask          Decimal          `json:"ask"`
+	Bid          Decimal          `json:"bid"`
+	Last         Decimal          `json:"last"`
+	Open         Decimal          `json:"open"`
+	Low          Decimal          `json:"low"`
+	High         Decimal          `json:"high"`
+	FeeCurrency  string           `json:"feeCurrency"`
+	Volume       Decimal          `json:"volume"`
+	QuoteVolume  Decimal          `json:"quoteVolume"`
+	Change       Decimal          `json:"change"`
+	PercentChage Decimal          `json:"percentChange"`
+	Quotes       map[string]Quote `json:"quotes,omitempty"`
 }
 
 // CurrencyData struct represents the data for all supported currencies
@@ -33,27 +40,176 @@ type CurrencyData struct {
 
 // MarketTicker represents the data for a single market ticker
 type MarketTicker struct {
-	Ask    string `json:"ask"`
-	Bid    string `json:"bid"`
-	Last   string `json:"last"`
-	Open   string `json:"open"`
-	Low    string `json:"low"`
-	High   string `json:"high"`
-	Volume string `json:"volume"`
+	Ask    Decimal `json:"ask"`
+	Bid    Decimal `json:"bid"`
+	Last   Decimal `json:"last"`
+	Open   Decimal `json:"open"`
+	Low    Decimal `json:"low"`
+	High   Decimal `json:"high"`
+	Volume Decimal `json:"volume"`
 }
 
-// Markets struct represents the data for all supported markets
+// changeAndPercent computes the absolute and percentage change of last versus
+// open. HitBTC's public ticker doesn't always populate these, so they're
+// derived server-side. When open is zero the percentage is reported as zero
+// rather than dividing by zero.
+func changeAndPercent(last, open Decimal) (change, percent Decimal) {
+	change = last.Sub(open)
+	if open.IsZero() {
+		return change, 0
+	}
+	percent, err := change.Div(open)
+	if err != nil {
+		return change, 0
+	}
+	return change, percent
+}
+
+// Markets struct represents the data for all supported markets. mu protects
+// Markets against concurrent reads from HTTP handlers and writes from the
+// poll loop and the HitBTC websocket stream.
 type Markets struct {
 	Markets map[string]MarketTicker `json:"markets"`
+	mu      sync.RWMutex
+}
+
+// Get returns the ticker stored under key, if any.
+func (m *Markets) Get(key string) (MarketTicker, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	ticker, ok := m.Markets[key]
+	return ticker, ok
+}
+
+// Snapshot returns a copy of the current market map, safe to range over
+// without holding the lock.
+func (m *Markets) Snapshot() map[string]MarketTicker {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	snapshot := make(map[string]MarketTicker, len(m.Markets))
+	for key, ticker := range m.Markets {
+		snapshot[key] = ticker
+	}
+	return snapshot
+}
+
+// Set stores ticker under key.
+func (m *Markets) Set(key string, ticker MarketTicker) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Markets[key] = ticker
+}
+
+// Replace swaps in a whole new market map, returning the diff between the old
+// and new data so callers can publish it to subscribers.
+func (m *Markets) Replace(newMarkets map[string]MarketTicker) []Update {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	updates := diffMarkets(m.Markets, newMarkets)
+	m.Markets = newMarkets
+	return updates
 }
 
 // Config struct represents the configuration data for the microservice
 type Config struct {
+	// Symbols is the default symbol list passed to a provider when it has no
+	// entry in ProviderSymbols. Exchanges spell symbols differently (e.g.
+	// HitBTC/Binance's "BTCUSD"/"BTCUSDT" vs Kucoin's "BTC-USDT"), so this is
+	// only safe to rely on when every enabled provider happens to share a
+	// convention; otherwise configure ProviderSymbols per exchange.
 	Symbols []string `json:"symbols"`
+	// Providers lists the exchange providers to poll, by name (e.g. "hitbtc",
+	// "binance", "kucoin", "bybit"). Defaults to []string{"hitbtc"} when empty.
+	Providers []string `json:"providers"`
+	// ProviderSymbols overrides Symbols on a per-provider basis, keyed by
+	// provider name, using that exchange's own symbol spelling (e.g.
+	// {"binance": ["BTCUSDT"], "kucoin": ["BTC-USDT"]}). Providers without an
+	// entry here fall back to Symbols.
+	ProviderSymbols map[string][]string `json:"providerSymbols"`
+	// Storage configures the historical tick store used for klines.
+	Storage StorageConfig `json:"storage"`
+	// FeeCurrency is reported on every Currency response. Defaults to "BTC".
+	FeeCurrency string `json:"feeCurrency"`
+	// Conversion configures the fiat/crypto conversion layer used for ?convert=.
+	Conversion ConversionConfig `json:"conversion"`
+}
+
+// resolveProviderSymbols builds the per-provider symbol list each provider in
+// providers should be polled with, falling back to defaultSymbols for any
+// provider without an entry in perProvider.
+func resolveProviderSymbols(providers []ExchangeProvider, perProvider map[string][]string, defaultSymbols []string) map[string][]string {
+	resolved := make(map[string][]string, len(providers))
+	for _, provider := range providers {
+		if symbols, ok := perProvider[provider.Name()]; ok && len(symbols) > 0 {
+			resolved[provider.Name()] = symbols
+			continue
+		}
+		resolved[provider.Name()] = defaultSymbols
+	}
+	return resolved
+}
+
+// marketKey builds the namespaced key used to store a ticker in Markets.Markets.
+func marketKey(exchange, symbol string) string {
+	return exchange + ":" + symbol
+}
+
+// newCurrency builds a Currency response from a market ticker, deriving
+// Change/PercentChage from Last and Open since upstream APIs don't always
+// populate them.
+func newCurrency(id string, ticker MarketTicker, feeCurrency string) Currency {
+	change, percent := changeAndPercent(ticker.Last, ticker.Open)
+	return Currency{
+		ID:           id,
+		FullName:     id,
+		Ask:          ticker.Ask,
+		Bid:          ticker.Bid,
+		Last:         ticker.Last,
+		Open:         ticker.Open,
+		Low:          ticker.Low,
+		High:         ticker.High,
+		FeeCurrency:  feeCurrency,
+		Volume:       ticker.Volume,
+		Change:       change,
+		PercentChage: percent,
+	}
+}
+
+// parseConvertParam splits a comma-separated ?convert=USD,EUR query value
+// into its target currency list.
+func parseConvertParam(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	var targets []string
+	for _, c := range strings.Split(raw, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			targets = append(targets, c)
+		}
+	}
+	return targets
 }
 
-// CurrencyHandler handles the GET /currency/{symbol} endpoint
-func CurrencyHandler(markets *Markets) http.HandlerFunc {
+// withQuotes populates currency.Quotes from the ?convert= query parameter, if
+// present and a ConversionService is configured.
+func withQuotes(r *http.Request, conversion *ConversionService, ticker MarketTicker, currency Currency) Currency {
+	targets := parseConvertParam(r.URL.Query().Get("convert"))
+	if conversion == nil || len(targets) == 0 {
+		return currency
+	}
+	quotes, err := conversion.Quote(r.Context(), ticker, targets)
+	if err != nil {
+		log.Printf("Error converting quotes: %v\n", err)
+		return currency
+	}
+	currency.Quotes = quotes
+	return currency
+}
+
+// CurrencyHandler handles the GET /currency/{symbol} endpoint. It resolves the
+// symbol against defaultExchange for backwards compatibility with clients that
+// predate multi-exchange support.
+func CurrencyHandler(markets *Markets, defaultExchange, feeCurrency string, conversion *ConversionService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Get the requested symbol and validate it
 		symbol := r.URL.Query().Get("symbol")
@@ -61,26 +217,14 @@ func CurrencyHandler(markets *Markets) http.HandlerFunc {
 			http.Error(w, "No symbol specified", http.StatusBadRequest)
 			return
 		}
-		if _, ok := markets.Markets[symbol]; !ok {
+		marketTicker, ok := markets.Get(marketKey(defaultExchange, symbol))
+		if !ok {
 			http.Error(w, "Invalid symbol specified", http.StatusBadRequest)
 			return
 		}
 
-		// Get the market ticker for the requested symbol
-		marketTicker := markets.Markets[symbol]
-
 		// Create a Currency object with the data from the market ticker
-		currency := Currency{
-			ID:          symbol,
-			FullName:    symbol,
-			Ask:         marketTicker.Ask,
-			Bid:         marketTicker.Bid,
-			Last:        marketTicker.Last,
-			Open:        marketTicker.Open,
-			Low:         marketTicker.Low,
-			High:        marketTicker.High,
-			FeeCurrency: "BTC", // Hard-coded for now
-		}
+		currency := withQuotes(r, conversion, marketTicker, newCurrency(symbol, marketTicker, feeCurrency))
 
 		// Convert the Currency object to JSON and write it to the response
 		js, err := json.Marshal(currency)
@@ -93,23 +237,41 @@ func CurrencyHandler(markets *Markets) http.HandlerFunc {
 	}
 }
 
+// ExchangeCurrencyHandler handles the GET /currency/{exchange}/{symbol} endpoint,
+// returning the ticker reported by a specific exchange provider.
+func ExchangeCurrencyHandler(markets *Markets, feeCurrency string, conversion *ConversionService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		exchange := r.URL.Query().Get("exchange")
+		symbol := r.URL.Query().Get("symbol")
+		if exchange == "" || symbol == "" {
+			http.Error(w, "No exchange/symbol specified", http.StatusBadRequest)
+			return
+		}
+		marketTicker, ok := markets.Get(marketKey(exchange, symbol))
+		if !ok {
+			http.Error(w, "Invalid exchange/symbol specified", http.StatusBadRequest)
+			return
+		}
+
+		currency := withQuotes(r, conversion, marketTicker, newCurrency(symbol, marketTicker, feeCurrency))
+
+		js, err := json.Marshal(currency)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(js)
+	}
+}
+
 // CurrenciesHandler handles the GET /currency/all endpoint
-func CurrenciesHandler(markets *Markets) http.HandlerFunc {
+func CurrenciesHandler(markets *Markets, feeCurrency string, conversion *ConversionService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Create a slice of Currency objects from the market tickers
 		var currencies []Currency
-		for symbol, marketTicker := range markets.Markets {
-			currency := Currency{
-				ID:          symbol,
-				FullName:    symbol,
-				Ask:         marketTicker.Ask,
-				Bid:         marketTicker.Bid,
-				Last:        marketTicker.Last,
-				Open:        marketTicker.Open,
-				Low:         marketTicker.Low,
-				High:        marketTicker.High,
-				FeeCurrency: "BTC", // Hard-coded for now
-			}
+		for key, marketTicker := range markets.Snapshot() {
+			currency := withQuotes(r, conversion, marketTicker, newCurrency(key, marketTicker, feeCurrency))
 			currencies = append(currencies, currency)
 		}
 
@@ -150,48 +312,100 @@ func getConfig() (*Config, error) {
 	return &config, nil
 }
 
-// getMarkets gets the latest market ticker data for all supported symbols and returns
-// a Markets object with the data
-func getMarkets(symbols []string) (*Markets, error) {
-	// Set the HitBTC API endpoint
-	endpoint := "https://api.hitbtc.com/api/2/public/ticker"
+// defaultProviders returns the providers to poll when Config.Providers is empty.
+func defaultProviders() []string {
+	return []string{"hitbtc"}
+}
 
-	// Make an HTTP request to the API endpoint
-	resp, err := http.Get(endpoint)
-	if err != nil {
-		return nil, err
+// loadProviders constructs the ExchangeProvider list for the given provider names,
+// falling back to defaultProviders when names is empty. Providers that fail to
+// construct are logged and skipped.
+func loadProviders(names []string) []ExchangeProvider {
+	if len(names) == 0 {
+		names = defaultProviders()
 	}
-	defer resp.Body.Close()
+	var providers []ExchangeProvider
+	for _, name := range names {
+		provider, err := newProvider(name)
+		if err != nil {
+			log.Printf("Skipping unknown provider %q: %v\n", name, err)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
 
-	// Read the response body into a byte slice
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+// getMarkets fans out FetchTickers to every provider in parallel and merges the
+// results into a single Markets object, namespacing each key by provider name
+// (e.g. "hitbtc:BTCUSD"). Each provider is queried with its own symbol list
+// from symbolsByProvider (see Config.ProviderSymbols), since exchanges don't
+// share a symbol spelling convention.
+func getMarkets(providers []ExchangeProvider, symbolsByProvider map[string][]string) (*Markets, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	type result struct {
+		provider ExchangeProvider
+		tickers  map[string]MarketTicker
+		err      error
 	}
 
-	// Unmarshal the JSON response into a map of MarketTicker objects
-	var marketTickers map[string]MarketTicker
-	err = json.Unmarshal(body, &marketTickers)
-	if err != nil {
-		return nil, err
+	results := make(chan result, len(providers))
+	var wg sync.WaitGroup
+	for _, provider := range providers {
+		wg.Add(1)
+		go func(provider ExchangeProvider) {
+			defer wg.Done()
+			start := time.Now()
+			tickers, err := provider.FetchTickers(ctx, symbolsByProvider[provider.Name()])
+			if provider.Name() == "hitbtc" {
+				hitbtcRequestDuration.Observe(time.Since(start).Seconds())
+			}
+			if err != nil {
+				fetchTotal.WithLabelValues(provider.Name(), "failure").Inc()
+			} else {
+				fetchTotal.WithLabelValues(provider.Name(), "success").Inc()
+			}
+			results <- result{provider: provider, tickers: tickers, err: err}
+		}(provider)
 	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
 
-	// Create a Markets object with the MarketTicker map
-	markets := Markets{
-		Markets: marketTickers,
+	merged := make(map[string]MarketTicker)
+	var errs []string
+	for res := range results {
+		if res.err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", res.provider.Name(), res.err))
+			continue
+		}
+		for symbol, ticker := range res.tickers {
+			merged[marketKey(res.provider.Name(), symbol)] = ticker
+		}
+	}
+
+	// Only fail outright if every provider errored; partial failures still
+	// produce usable data from the providers that succeeded.
+	if len(errs) == len(providers) && len(providers) > 0 {
+		return nil, fmt.Errorf("all providers failed: %s", strings.Join(errs, "; "))
+	}
+	if len(errs) > 0 {
+		log.Printf("Some providers failed: %s\n", strings.Join(errs, "; "))
 	}
 
-	return &markets, nil
+	return &Markets{Markets: merged}, nil
 }
 
 // updateMarkets periodically updates the market ticker data for all supported symbols and
-// updates the Markets object with the new data
-func updateMarkets(markets *Markets, symbols []string) {
-	// Set the update interval to 10 seconds
-	updateInterval := 10 * time.Second
-
+// updates the Markets object with the new data, publishing a diff of changed
+// tickers to hub so subscribed websocket clients get pushed the delta, and
+// appending every successful poll to store for historical klines.
+func updateMarkets(markets *Markets, providers []ExchangeProvider, symbolsByProvider map[string][]string, hub *tickerHub, store TickStore, tracker *freshnessTracker) {
 	// Create a ticker to trigger the update at the specified interval
-	ticker := time.NewTicker(updateInterval)
+	ticker := time.NewTicker(pollInterval)
 
 	// Use a WaitGroup to block until all goroutines are finished
 	var wg sync.WaitGroup
@@ -205,11 +419,21 @@ func updateMarkets(markets *Markets, symbols []string) {
 				return
 			case <-ticker.C:
 				log.Println("Updating markets...")
-				newMarkets, err := getMarkets(symbols)
+				newMarkets, err := getMarkets(providers, symbolsByProvider)
 				if err != nil {
 					log.Printf("Error updating markets: %v\n", err)
 				} else {
-					*markets = *newMarkets
+					for _, update := range markets.Replace(newMarkets.Markets) {
+						hub.Updates <- update
+					}
+
+					now := time.Now()
+					for key, ticker := range newMarkets.Markets {
+						if err := store.RecordTick(context.Background(), key, ticker, now); err != nil {
+							log.Printf("Error recording tick for %s: %v\n", key, err)
+						}
+						tracker.touch(key, now)
+					}
 				}
 			}
 		}
@@ -233,30 +457,120 @@ func main() {
 	if err != nil {
 		log.Fatalf("Error loading configuration: %v\n", err)
 	}
-	symbols := config.Symbols
 
-	// Get the initial market ticker data
-	markets, err := getMarkets(symbols)
+	// Build the list of enabled exchange providers
+	providers := loadProviders(config.Providers)
+	if len(providers) == 0 {
+		log.Fatalf("No exchange providers could be loaded from config\n")
+	}
+	defaultExchange := providers[0].Name()
+
+	// Resolve the symbol list each provider is polled with: each exchange
+	// spells symbols differently, so this prefers a per-provider override
+	// over the shared Config.Symbols default.
+	symbolsByProvider := resolveProviderSymbols(providers, config.ProviderSymbols, config.Symbols)
+
+	// Get the initial market ticker data (a one-time REST snapshot; HitBTC
+	// switches to the realtime websocket stream below)
+	markets, err := getMarkets(providers, symbolsByProvider)
 	if err != nil {
 		log.Fatalf("Error getting initial markets: %v\n", err)
 	}
 
-	// Start the goroutine to update the market ticker data periodically
-	go updateMarkets(markets, symbols)
+	tracker := newFreshnessTracker()
+	now := time.Now()
+	for key := range markets.Markets {
+		tracker.touch(key, now)
+	}
+	go runFreshnessMonitor(context.Background(), tracker, 5*time.Second)
+
+	// HitBTC is streamed over its public websocket instead of being re-polled
+	// every tick, so drop it from the REST poll providers.
+	var pollProviders []ExchangeProvider
+	streamHitBTC := false
+	for _, provider := range providers {
+		if provider.Name() == "hitbtc" {
+			streamHitBTC = true
+			continue
+		}
+		pollProviders = append(pollProviders, provider)
+	}
+
+	// Open the historical tick store used for klines
+	dbPath := config.Storage.DBPath
+	if dbPath == "" {
+		dbPath = "ticks.db"
+	}
+	store, err := NewSQLiteStore(dbPath, config.Storage.MaxAgePerPeriod, config.Storage.MaxTickAge)
+	if err != nil {
+		log.Fatalf("Error opening tick store: %v\n", err)
+	}
+	defer store.Close()
+	go runCompaction(context.Background(), store, time.Hour)
+
+	feeCurrency := config.FeeCurrency
+	if feeCurrency == "" {
+		feeCurrency = "BTC"
+	}
+
+	conversion, err := NewConversionService(config.Conversion)
+	if err != nil {
+		log.Fatalf("Error configuring conversion service: %v\n", err)
+	}
+
+	// Start the ticker hub that fans update events out to websocket clients
+	hub := newTickerHub(feeCurrency)
+	go hub.run()
+
+	// Start the goroutine to update the REST-polled market ticker data periodically
+	go updateMarkets(markets, pollProviders, symbolsByProvider, hub, store, tracker)
+
+	// Start the HitBTC websocket stream supervisor
+	if streamHitBTC {
+		stream := NewHitBTCStream(symbolsByProvider["hitbtc"], markets, hub, store, tracker)
+		go stream.Run(context.Background())
+	}
 
 	// Set up the HTTP server and handlers
+	http.HandleFunc("/healthz", HealthzHandler)
+	http.HandleFunc("/readyz", ReadyzHandler(tracker, 3*pollInterval))
+	http.Handle("/metrics", MetricsHandler())
+	http.HandleFunc("/ws/ticker", TickerWebSocketHandler(hub))
+	http.HandleFunc("/rates", withMetrics(RatesHandler(conversion)))
 	http.HandleFunc("/currency", func(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/currency/all", http.StatusMovedPermanently)
 	})
-	http.HandleFunc("/currency/", func(w http.ResponseWriter, r *http.Request) {
-		symbol := r.URL.Path[len("/currency/"):]
-		if symbol != "all" {
-			r.URL.Query().Set("symbol", symbol)
-			CurrencyHandler(markets)(w, r)
-		} else {
-			CurrenciesHandler(markets)(w, r)
+	http.HandleFunc("/currency/", withMetrics(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path[len("/currency/"):]
+		if path == "all" {
+			CurrenciesHandler(markets, feeCurrency, conversion)(w, r)
+			return
 		}
-	})
+
+		// /currency/{symbol}/klines serves historical OHLCV buckets;
+		// /currency/{exchange}/{symbol} routes to a specific provider;
+		// anything else is /currency/{symbol} against defaultExchange.
+		if head, tail, ok := strings.Cut(path, "/"); ok {
+			if tail == "klines" {
+				q := r.URL.Query()
+				q.Set("symbol", head)
+				r.URL.RawQuery = q.Encode()
+				KlinesHandler(store, defaultExchange)(w, r)
+				return
+			}
+			q := r.URL.Query()
+			q.Set("exchange", head)
+			q.Set("symbol", tail)
+			r.URL.RawQuery = q.Encode()
+			ExchangeCurrencyHandler(markets, feeCurrency, conversion)(w, r)
+			return
+		}
+
+		q := r.URL.Query()
+		q.Set("symbol", path)
+		r.URL.RawQuery = q.Encode()
+		CurrencyHandler(markets, defaultExchange, feeCurrency, conversion)(w, r)
+	}))
 
 	// Start the server on port 8080
 	log.Println("Starting server...")
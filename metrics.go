@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	fetchTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "currency_service_fetch_total",
+		Help: "Upstream exchange fetches, partitioned by provider and result.",
+	}, []string{"provider", "result"})
+
+	hitbtcRequestDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "currency_service_hitbtc_request_duration_seconds",
+		Help: "Latency of requests to the HitBTC API.",
+	})
+
+	lastUpdateAgeSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "currency_service_last_update_age_seconds",
+		Help: "Seconds since each market symbol last received an update.",
+	}, []string{"symbol"})
+
+	handlerResponsesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "currency_service_handler_responses_total",
+		Help: "HTTP handler responses, partitioned by status class (4xx/5xx only).",
+	}, []string{"class"})
+)
+
+// statusRecorder wraps a ResponseWriter to capture the status code written by
+// the wrapped handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// withMetrics wraps next, recording a currency_service_handler_responses_total
+// increment whenever it responds with a 4xx or 5xx status.
+func withMetrics(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r)
+		switch {
+		case rec.status >= 500:
+			handlerResponsesTotal.WithLabelValues("5xx").Inc()
+		case rec.status >= 400:
+			handlerResponsesTotal.WithLabelValues("4xx").Inc()
+		}
+	}
+}
+
+// freshnessTracker records when each market key was last updated, backing
+// both the last-update-age gauge and the /readyz check.
+type freshnessTracker struct {
+	mu          sync.Mutex
+	lastSeen    map[string]time.Time
+	initialized bool
+}
+
+func newFreshnessTracker() *freshnessTracker {
+	return &freshnessTracker{lastSeen: make(map[string]time.Time)}
+}
+
+// touch records that key was updated at t.
+func (f *freshnessTracker) touch(key string, t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lastSeen[key] = t
+	f.initialized = true
+}
+
+// touchAll records that every key in keys was updated at t.
+func (f *freshnessTracker) touchAll(keys []string, t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, key := range keys {
+		f.lastSeen[key] = t
+	}
+	f.initialized = true
+}
+
+// ready reports whether the tracker has seen an initial update and no tracked
+// key's age exceeds maxAge. The returned string names the stalest offending
+// symbol for diagnostics.
+func (f *freshnessTracker) ready(maxAge time.Duration) (bool, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.initialized {
+		return false, "no successful update yet"
+	}
+	now := time.Now()
+	for key, seen := range f.lastSeen {
+		if now.Sub(seen) > maxAge {
+			return false, key
+		}
+	}
+	return true, ""
+}
+
+// publishAges pushes the current age of every tracked key into the
+// last-update-age gauge.
+func (f *freshnessTracker) publishAges() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := time.Now()
+	for key, seen := range f.lastSeen {
+		lastUpdateAgeSeconds.WithLabelValues(key).Set(now.Sub(seen).Seconds())
+	}
+}
+
+// runFreshnessMonitor periodically republishes tracked ages until ctx is
+// cancelled.
+func runFreshnessMonitor(ctx context.Context, tracker *freshnessTracker, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			tracker.publishAges()
+		}
+	}
+}
+
+// HealthzHandler reports that the process is up.
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// ReadyzHandler reports 503 until the first successful getMarkets completes,
+// and whenever any tracked symbol's last-update age exceeds maxAge.
+func ReadyzHandler(tracker *freshnessTracker, maxAge time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ok, reason := tracker.ready(maxAge); !ok {
+			http.Error(w, "not ready: "+reason, http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	}
+}
+
+// MetricsHandler exposes the Prometheus registry.
+func MetricsHandler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// ExchangeProvider is implemented by anything that can fetch ticker data for a
+// set of symbols from an upstream exchange API.
+type ExchangeProvider interface {
+	// Name returns the short, lowercase identifier for the provider (e.g. "hitbtc").
+	// It is used as the namespace prefix for merged market keys.
+	Name() string
+	// FetchTickers fetches the latest ticker data for the given symbols.
+	FetchTickers(ctx context.Context, symbols []string) (map[string]MarketTicker, error)
+}
+
+// httpGetJSON performs an HTTP GET request and decodes the JSON response body into v.
+func httpGetJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// buildTicker parses the raw string fields an exchange reports into a
+// MarketTicker, returning an error if any field fails to parse.
+func buildTicker(ask, bid, last, open, low, high, volume string) (MarketTicker, error) {
+	fields := []*string{&ask, &bid, &last, &open, &low, &high, &volume}
+	parsed := make([]Decimal, len(fields))
+	for i, f := range fields {
+		d, err := ParseDecimal(*f)
+		if err != nil {
+			return MarketTicker{}, err
+		}
+		parsed[i] = d
+	}
+	return MarketTicker{
+		Ask:    parsed[0],
+		Bid:    parsed[1],
+		Last:   parsed[2],
+		Open:   parsed[3],
+		Low:    parsed[4],
+		High:   parsed[5],
+		Volume: parsed[6],
+	}, nil
+}
+
+// symbolSet builds a lookup set from a slice of symbols for quick membership checks.
+func symbolSet(symbols []string) map[string]bool {
+	set := make(map[string]bool, len(symbols))
+	for _, s := range symbols {
+		set[strings.ToUpper(s)] = true
+	}
+	return set
+}
+
+// HitBTCProvider fetches ticker data from the HitBTC public API.
+type HitBTCProvider struct{}
+
+func (p *HitBTCProvider) Name() string { return "hitbtc" }
+
+func (p *HitBTCProvider) FetchTickers(ctx context.Context, symbols []string) (map[string]MarketTicker, error) {
+	var all map[string]MarketTicker
+	if err := httpGetJSON(ctx, "https://api.hitbtc.com/api/2/public/ticker", &all); err != nil {
+		return nil, err
+	}
+	return filterTickers(all, symbols), nil
+}
+
+// BinanceProvider fetches ticker data from the Binance public API.
+type BinanceProvider struct{}
+
+func (p *BinanceProvider) Name() string { return "binance" }
+
+type binanceTicker struct {
+	Symbol    string `json:"symbol"`
+	BidPrice  string `json:"bidPrice"`
+	AskPrice  string `json:"askPrice"`
+	LastPrice string `json:"lastPrice"`
+	OpenPrice string `json:"openPrice"`
+	LowPrice  string `json:"lowPrice"`
+	HighPrice string `json:"highPrice"`
+	Volume    string `json:"volume"`
+}
+
+func (p *BinanceProvider) FetchTickers(ctx context.Context, symbols []string) (map[string]MarketTicker, error) {
+	var raw []binanceTicker
+	if err := httpGetJSON(ctx, "https://api.binance.com/api/v3/ticker/24hr", &raw); err != nil {
+		return nil, err
+	}
+	wanted := symbolSet(symbols)
+	out := make(map[string]MarketTicker)
+	for _, t := range raw {
+		if !wanted[strings.ToUpper(t.Symbol)] {
+			continue
+		}
+		ticker, err := buildTicker(t.AskPrice, t.BidPrice, t.LastPrice, t.OpenPrice, t.LowPrice, t.HighPrice, t.Volume)
+		if err != nil {
+			log.Printf("binance: skipping %s: %v\n", t.Symbol, err)
+			continue
+		}
+		out[t.Symbol] = ticker
+	}
+	return out, nil
+}
+
+// KucoinProvider fetches ticker data from the Kucoin public API.
+type KucoinProvider struct{}
+
+func (p *KucoinProvider) Name() string { return "kucoin" }
+
+type kucoinTickerResponse struct {
+	Data struct {
+		Ticker []struct {
+			Symbol string `json:"symbol"`
+			Buy    string `json:"buy"`
+			Sell   string `json:"sell"`
+			Last   string `json:"last"`
+			Open   string `json:"open"`
+			Low    string `json:"low"`
+			High   string `json:"high"`
+			Vol    string `json:"vol"`
+		} `json:"ticker"`
+	} `json:"data"`
+}
+
+func (p *KucoinProvider) FetchTickers(ctx context.Context, symbols []string) (map[string]MarketTicker, error) {
+	var resp kucoinTickerResponse
+	if err := httpGetJSON(ctx, "https://api.kucoin.com/api/v1/market/allTickers", &resp); err != nil {
+		return nil, err
+	}
+	wanted := symbolSet(symbols)
+	out := make(map[string]MarketTicker)
+	for _, t := range resp.Data.Ticker {
+		symbol := strings.ReplaceAll(t.Symbol, "-", "")
+		if !wanted[strings.ToUpper(symbol)] {
+			continue
+		}
+		ticker, err := buildTicker(t.Sell, t.Buy, t.Last, t.Open, t.Low, t.High, t.Vol)
+		if err != nil {
+			log.Printf("kucoin: skipping %s: %v\n", symbol, err)
+			continue
+		}
+		out[symbol] = ticker
+	}
+	return out, nil
+}
+
+// BybitProvider fetches ticker data from the Bybit public API.
+type BybitProvider struct{}
+
+func (p *BybitProvider) Name() string { return "bybit" }
+
+type bybitTickerResponse struct {
+	Result struct {
+		List []struct {
+			Symbol    string `json:"symbol"`
+			BidPrice  string `json:"bid1Price"`
+			AskPrice  string `json:"ask1Price"`
+			LastPrice string `json:"lastPrice"`
+			HighPrice string `json:"highPrice24h"`
+			LowPrice  string `json:"lowPrice24h"`
+			Volume    string `json:"volume24h"`
+			PrevPrice string `json:"prevPrice24h"`
+		} `json:"list"`
+	} `json:"result"`
+}
+
+func (p *BybitProvider) FetchTickers(ctx context.Context, symbols []string) (map[string]MarketTicker, error) {
+	var resp bybitTickerResponse
+	if err := httpGetJSON(ctx, "https://api.bybit.com/v5/market/tickers?category=spot", &resp); err != nil {
+		return nil, err
+	}
+	wanted := symbolSet(symbols)
+	out := make(map[string]MarketTicker)
+	for _, t := range resp.Result.List {
+		if !wanted[strings.ToUpper(t.Symbol)] {
+			continue
+		}
+		ticker, err := buildTicker(t.AskPrice, t.BidPrice, t.LastPrice, t.PrevPrice, t.LowPrice, t.HighPrice, t.Volume)
+		if err != nil {
+			log.Printf("bybit: skipping %s: %v\n", t.Symbol, err)
+			continue
+		}
+		out[t.Symbol] = ticker
+	}
+	return out, nil
+}
+
+// filterTickers restricts a map of market tickers down to the requested symbols.
+func filterTickers(all map[string]MarketTicker, symbols []string) map[string]MarketTicker {
+	wanted := symbolSet(symbols)
+	out := make(map[string]MarketTicker, len(symbols))
+	for symbol, ticker := range all {
+		if wanted[strings.ToUpper(symbol)] {
+			out[symbol] = ticker
+		}
+	}
+	return out
+}
+
+// newProvider constructs the ExchangeProvider registered under the given name.
+func newProvider(name string) (ExchangeProvider, error) {
+	switch strings.ToLower(name) {
+	case "hitbtc":
+		return &HitBTCProvider{}, nil
+	case "binance":
+		return &BinanceProvider{}, nil
+	case "kucoin":
+		return &KucoinProvider{}, nil
+	case "bybit":
+		return &BybitProvider{}, nil
+	default:
+		return nil, fmt.Errorf("unknown exchange provider: %s", name)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Kline is a single OHLCV rollup bucket, shaped to match the kline responses
+// returned by Binance/Kucoin/Bybit clients.
+type Kline struct {
+	OpenTime int64   `json:"openTime"`
+	Open     Decimal `json:"open"`
+	High     Decimal `json:"high"`
+	Low      Decimal `json:"low"`
+	Close    Decimal `json:"close"`
+	Volume   Decimal `json:"volume"`
+}
+
+// periodDurations maps the supported kline periods to their bucket width.
+var periodDurations = map[string]time.Duration{
+	"1m": time.Minute,
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+}
+
+// StorageConfig configures the historical tick store.
+type StorageConfig struct {
+	// DBPath is the SQLite database file to open. Defaults to "ticks.db".
+	DBPath string `json:"dbPath"`
+	// MaxAgePerPeriod bounds how long kline buckets are retained, keyed by
+	// period (e.g. "1m", "5m", "1h", "1d"). Periods absent from this map are
+	// kept indefinitely.
+	MaxAgePerPeriod map[string]time.Duration `json:"maxAgePerPeriod"`
+	// MaxTickAge bounds how long raw ticks are retained before Compact prunes
+	// them. Raw ticks are written on every poll/push and already folded into
+	// the kline buckets, so they're only kept around for this long. Zero
+	// keeps them indefinitely.
+	MaxTickAge time.Duration `json:"maxTickAge"`
+}
+
+// TickStore persists ticks and rolls them up into OHLCV buckets. SQLite is the
+// default implementation; Postgres/InfluxDB backends can satisfy the same
+// interface.
+type TickStore interface {
+	// RecordTick appends a tick and folds it into the current bucket for
+	// every supported period, starting a new bucket on period boundary
+	// crossings.
+	RecordTick(ctx context.Context, key string, ticker MarketTicker, ts time.Time) error
+	// Klines returns up to limit buckets for key/period, most recent last.
+	Klines(ctx context.Context, key, period string, limit int) ([]Kline, error)
+	// Compact deletes buckets older than the configured retention per period.
+	Compact(ctx context.Context) error
+	Close() error
+}
+
+// SQLiteStore is the default TickStore, backed by database/sql and the
+// mattn/go-sqlite3 driver.
+type SQLiteStore struct {
+	db         *sql.DB
+	retention  map[string]time.Duration
+	tickMaxAge time.Duration
+}
+
+// NewSQLiteStore opens (creating if necessary) the SQLite database at path
+// and ensures the ticks/klines schema exists. tickMaxAge bounds how long raw
+// ticks are retained; zero keeps them indefinitely.
+func NewSQLiteStore(path string, retention map[string]time.Duration, tickMaxAge time.Duration) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	schema := `
+CREATE TABLE IF NOT EXISTS ticks (
+	key TEXT NOT NULL,
+	ts INTEGER NOT NULL,
+	ask INTEGER NOT NULL,
+	bid INTEGER NOT NULL,
+	last INTEGER NOT NULL,
+	open INTEGER NOT NULL,
+	low INTEGER NOT NULL,
+	high INTEGER NOT NULL,
+	volume INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_ticks_key_ts ON ticks(key, ts);
+
+CREATE TABLE IF NOT EXISTS klines (
+	key TEXT NOT NULL,
+	period TEXT NOT NULL,
+	open_time INTEGER NOT NULL,
+	open INTEGER NOT NULL,
+	high INTEGER NOT NULL,
+	low INTEGER NOT NULL,
+	close INTEGER NOT NULL,
+	volume INTEGER NOT NULL,
+	PRIMARY KEY (key, period, open_time)
+);
+`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStore{db: db, retention: retention, tickMaxAge: tickMaxAge}, nil
+}
+
+func (s *SQLiteStore) Close() error { return s.db.Close() }
+
+func (s *SQLiteStore) RecordTick(ctx context.Context, key string, ticker MarketTicker, ts time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO ticks (key, ts, ask, bid, last, open, low, high, volume) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		key, ts.Unix(), ticker.Ask, ticker.Bid, ticker.Last, ticker.Open, ticker.Low, ticker.High, ticker.Volume,
+	)
+	if err != nil {
+		return err
+	}
+
+	for period, bucketWidth := range periodDurations {
+		if err := s.rollup(ctx, key, period, bucketWidth, ticker, ts); err != nil {
+			return fmt.Errorf("rollup %s/%s: %w", key, period, err)
+		}
+	}
+	return nil
+}
+
+// rollup updates the current bucket for key/period in place, or inserts a new
+// bucket when ts crosses into the next period boundary.
+func (s *SQLiteStore) rollup(ctx context.Context, key, period string, bucketWidth time.Duration, ticker MarketTicker, ts time.Time) error {
+	openTime := ts.Unix() / int64(bucketWidth.Seconds()) * int64(bucketWidth.Seconds())
+
+	var high, low Decimal
+	err := s.db.QueryRowContext(ctx,
+		`SELECT high, low FROM klines WHERE key = ? AND period = ? AND open_time = ?`,
+		key, period, openTime,
+	).Scan(&high, &low)
+
+	switch err {
+	case sql.ErrNoRows:
+		_, err = s.db.ExecContext(ctx,
+			`INSERT INTO klines (key, period, open_time, open, high, low, close, volume) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			key, period, openTime, ticker.Open, ticker.High, ticker.Low, ticker.Last, ticker.Volume,
+		)
+		return err
+	case nil:
+		if ticker.High > high {
+			high = ticker.High
+		}
+		if ticker.Low < low {
+			low = ticker.Low
+		}
+		_, err = s.db.ExecContext(ctx,
+			`UPDATE klines SET high = ?, low = ?, close = ?, volume = ? WHERE key = ? AND period = ? AND open_time = ?`,
+			high, low, ticker.Last, ticker.Volume, key, period, openTime,
+		)
+		return err
+	default:
+		return err
+	}
+}
+
+func (s *SQLiteStore) Klines(ctx context.Context, key, period string, limit int) ([]Kline, error) {
+	if _, ok := periodDurations[period]; !ok {
+		return nil, fmt.Errorf("unsupported period: %s", period)
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT open_time, open, high, low, close, volume FROM klines
+		 WHERE key = ? AND period = ? ORDER BY open_time DESC LIMIT ?`,
+		key, period, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var klines []Kline
+	for rows.Next() {
+		var k Kline
+		if err := rows.Scan(&k.OpenTime, &k.Open, &k.High, &k.Low, &k.Close, &k.Volume); err != nil {
+			return nil, err
+		}
+		klines = append(klines, k)
+	}
+
+	// Flip back into ascending order, matching the shape other exchange APIs return.
+	for i, j := 0, len(klines)-1; i < j; i, j = i+1, j-1 {
+		klines[i], klines[j] = klines[j], klines[i]
+	}
+	return klines, rows.Err()
+}
+
+func (s *SQLiteStore) Compact(ctx context.Context) error {
+	for period, maxAge := range s.retention {
+		cutoff := time.Now().Add(-maxAge).Unix()
+		if _, err := s.db.ExecContext(ctx,
+			`DELETE FROM klines WHERE period = ? AND open_time < ?`, period, cutoff,
+		); err != nil {
+			return fmt.Errorf("compact %s: %w", period, err)
+		}
+	}
+	if s.tickMaxAge > 0 {
+		cutoff := time.Now().Add(-s.tickMaxAge).Unix()
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM ticks WHERE ts < ?`, cutoff); err != nil {
+			return fmt.Errorf("compact ticks: %w", err)
+		}
+	}
+	return nil
+}
+
+// runCompaction periodically compacts store according to its retention
+// policy until ctx is cancelled.
+func runCompaction(ctx context.Context, store TickStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := store.Compact(ctx); err != nil {
+				log.Printf("Error compacting tick store: %v\n", err)
+			}
+		}
+	}
+}
+
+// KlinesHandler handles GET /currency/{symbol}/klines?period=1m&limit=500.
+func KlinesHandler(store TickStore, defaultExchange string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		symbol := r.URL.Query().Get("symbol")
+		period := r.URL.Query().Get("period")
+		if symbol == "" || period == "" {
+			http.Error(w, "symbol and period are required", http.StatusBadRequest)
+			return
+		}
+
+		limit := 500
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed <= 0 {
+				http.Error(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+
+		klines, err := store.Klines(r.Context(), marketKey(defaultExchange, symbol), period, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		js, err := json.Marshal(klines)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(js)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T, retention map[string]time.Duration, tickMaxAge time.Duration) *SQLiteStore {
+	t.Helper()
+	store, err := NewSQLiteStore(":memory:", retention, tickMaxAge)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func tickerAt(last, open, low, high Decimal) MarketTicker {
+	return MarketTicker{Last: last, Open: open, Low: low, High: high}
+}
+
+func TestRollupUpdatesBucketInPlace(t *testing.T) {
+	store := newTestStore(t, nil, 0)
+	ctx := context.Background()
+	key := "hitbtc:BTCUSD"
+
+	// bucketStart is aligned to a 1m boundary so both ticks below land in the
+	// same bucket.
+	bucketStart := time.Unix(1700000000/60*60, 0).UTC()
+
+	first := tickerAt(Decimal(10000), Decimal(10000), Decimal(9900), Decimal(10100))
+	if err := store.RecordTick(ctx, key, first, bucketStart); err != nil {
+		t.Fatalf("RecordTick: %v", err)
+	}
+
+	// Same bucket (30s later): a new high and a new low should be folded in,
+	// and close should move to the latest Last.
+	second := tickerAt(Decimal(10050), Decimal(10000), Decimal(9800), Decimal(10200))
+	if err := store.RecordTick(ctx, key, second, bucketStart.Add(30*time.Second)); err != nil {
+		t.Fatalf("RecordTick: %v", err)
+	}
+
+	klines, err := store.Klines(ctx, key, "1m", 10)
+	if err != nil {
+		t.Fatalf("Klines: %v", err)
+	}
+	if len(klines) != 1 {
+		t.Fatalf("len(klines) = %d, want 1 bucket", len(klines))
+	}
+	k := klines[0]
+	if k.Open != first.Open {
+		t.Errorf("open = %s, want %s (should stay at the bucket's first tick)", k.Open, first.Open)
+	}
+	if k.High != second.High {
+		t.Errorf("high = %s, want %s", k.High, second.High)
+	}
+	if k.Low != second.Low {
+		t.Errorf("low = %s, want %s", k.Low, second.Low)
+	}
+	if k.Close != second.Last {
+		t.Errorf("close = %s, want %s (should move to the latest last)", k.Close, second.Last)
+	}
+}
+
+func TestRollupCrossesBucketBoundary(t *testing.T) {
+	store := newTestStore(t, nil, 0)
+	ctx := context.Background()
+	key := "hitbtc:BTCUSD"
+
+	bucketStart := time.Unix(1700000000/60*60, 0).UTC()
+
+	first := tickerAt(Decimal(10000), Decimal(10000), Decimal(9900), Decimal(10100))
+	if err := store.RecordTick(ctx, key, first, bucketStart); err != nil {
+		t.Fatalf("RecordTick: %v", err)
+	}
+
+	// 61s later crosses into the next 1m bucket, so this should start a new
+	// bucket rather than folding into the first.
+	next := tickerAt(Decimal(11000), Decimal(11000), Decimal(10900), Decimal(11100))
+	if err := store.RecordTick(ctx, key, next, bucketStart.Add(61*time.Second)); err != nil {
+		t.Fatalf("RecordTick: %v", err)
+	}
+
+	klines, err := store.Klines(ctx, key, "1m", 10)
+	if err != nil {
+		t.Fatalf("Klines: %v", err)
+	}
+	if len(klines) != 2 {
+		t.Fatalf("len(klines) = %d, want 2 buckets", len(klines))
+	}
+	if klines[0].OpenTime >= klines[1].OpenTime {
+		t.Errorf("klines not in ascending open_time order: %+v", klines)
+	}
+	if klines[1].Open != next.Open {
+		t.Errorf("second bucket open = %s, want %s", klines[1].Open, next.Open)
+	}
+}
+
+func TestCompactPrunesOldKlinesPerPeriod(t *testing.T) {
+	store := newTestStore(t, map[string]time.Duration{"1m": time.Hour}, 0)
+	ctx := context.Background()
+	key := "hitbtc:BTCUSD"
+
+	old := time.Now().Add(-2 * time.Hour)
+	recent := time.Now().Add(-time.Minute)
+	ticker := tickerAt(Decimal(10000), Decimal(10000), Decimal(9900), Decimal(10100))
+
+	if err := store.RecordTick(ctx, key, ticker, old); err != nil {
+		t.Fatalf("RecordTick(old): %v", err)
+	}
+	if err := store.RecordTick(ctx, key, ticker, recent); err != nil {
+		t.Fatalf("RecordTick(recent): %v", err)
+	}
+
+	if err := store.Compact(ctx); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	klines, err := store.Klines(ctx, key, "1m", 10)
+	if err != nil {
+		t.Fatalf("Klines: %v", err)
+	}
+	if len(klines) != 1 {
+		t.Fatalf("len(klines) = %d after compact, want 1 (old bucket pruned)", len(klines))
+	}
+}
+
+func TestCompactPrunesOldTicks(t *testing.T) {
+	store := newTestStore(t, nil, time.Hour)
+	ctx := context.Background()
+	key := "hitbtc:BTCUSD"
+
+	old := time.Now().Add(-2 * time.Hour)
+	recent := time.Now().Add(-time.Minute)
+	ticker := tickerAt(Decimal(10000), Decimal(10000), Decimal(9900), Decimal(10100))
+
+	if err := store.RecordTick(ctx, key, ticker, old); err != nil {
+		t.Fatalf("RecordTick(old): %v", err)
+	}
+	if err := store.RecordTick(ctx, key, ticker, recent); err != nil {
+		t.Fatalf("RecordTick(recent): %v", err)
+	}
+
+	if err := store.Compact(ctx); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	var count int
+	if err := store.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM ticks WHERE key = ?`, key).Scan(&count); err != nil {
+		t.Fatalf("counting ticks: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("ticks remaining after compact = %d, want 1 (old tick pruned)", count)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Update represents a single changed market ticker, keyed the same way as
+// Markets.Markets (e.g. "hitbtc:BTCUSD").
+type Update struct {
+	Key    string
+	Ticker MarketTicker
+}
+
+// subscribeFrame is the message clients send to choose which symbols they
+// want pushed to them, e.g. {"action":"subscribe","symbols":["BTCUSD"]}.
+type subscribeFrame struct {
+	Action  string   `json:"action"`
+	Symbols []string `json:"symbols"`
+}
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// The service is read from arbitrary origins by design; it only ever
+	// serves public market data.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsClient represents a single subscribed websocket connection.
+type wsClient struct {
+	conn    *websocket.Conn
+	send    chan Currency
+	symbols map[string]bool
+	mu      sync.Mutex
+}
+
+func (c *wsClient) wants(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.symbols) == 0 {
+		return true
+	}
+	_, symbol, ok := strings.Cut(key, ":")
+	if !ok {
+		symbol = key
+	}
+	return c.symbols[strings.ToUpper(symbol)]
+}
+
+func (c *wsClient) setSymbols(symbols []string) {
+	set := make(map[string]bool, len(symbols))
+	for _, s := range symbols {
+		set[strings.ToUpper(s)] = true
+	}
+	c.mu.Lock()
+	c.symbols = set
+	c.mu.Unlock()
+}
+
+// tickerHub fans out Update events published on Updates to every subscribed
+// websocket client.
+type tickerHub struct {
+	Updates chan Update
+
+	feeCurrency string
+	mu          sync.Mutex
+	clients     map[*wsClient]bool
+}
+
+func newTickerHub(feeCurrency string) *tickerHub {
+	return &tickerHub{
+		Updates:     make(chan Update, 256),
+		clients:     make(map[*wsClient]bool),
+		feeCurrency: feeCurrency,
+	}
+}
+
+// run consumes published updates and forwards them to every client that is
+// subscribed to the relevant symbol. It should be started in its own goroutine.
+func (h *tickerHub) run() {
+	for update := range h.Updates {
+		_, symbol, ok := strings.Cut(update.Key, ":")
+		if !ok {
+			symbol = update.Key
+		}
+		currency := newCurrency(symbol, update.Ticker, h.feeCurrency)
+
+		h.mu.Lock()
+		for client := range h.clients {
+			if !client.wants(update.Key) {
+				continue
+			}
+			select {
+			case client.send <- currency:
+			default:
+				// Client is too slow to keep up; drop it rather than block the hub.
+				h.removeLocked(client)
+			}
+		}
+		h.mu.Unlock()
+	}
+}
+
+func (h *tickerHub) add(client *wsClient) {
+	h.mu.Lock()
+	h.clients[client] = true
+	h.mu.Unlock()
+}
+
+func (h *tickerHub) remove(client *wsClient) {
+	h.mu.Lock()
+	h.removeLocked(client)
+	h.mu.Unlock()
+}
+
+func (h *tickerHub) removeLocked(client *wsClient) {
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		close(client.send)
+	}
+}
+
+// diffMarkets compares the old and new Markets maps and returns an Update for
+// every key whose ticker changed or was added.
+func diffMarkets(old, new map[string]MarketTicker) []Update {
+	var updates []Update
+	for key, ticker := range new {
+		if oldTicker, ok := old[key]; !ok || oldTicker != ticker {
+			updates = append(updates, Update{Key: key, Ticker: ticker})
+		}
+	}
+	return updates
+}
+
+// TickerWebSocketHandler handles the GET /ws/ticker endpoint, upgrading the
+// connection and streaming Currency updates for the symbols the client
+// subscribes to.
+func TickerWebSocketHandler(hub *tickerHub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := wsUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Printf("ws upgrade failed: %v\n", err)
+			return
+		}
+
+		client := &wsClient{conn: conn, send: make(chan Currency, 32)}
+		hub.add(client)
+
+		go client.writePump()
+		client.readPump(hub)
+	}
+}
+
+// readPump reads subscribe frames from the client until the connection closes.
+func (c *wsClient) readPump(hub *tickerHub) {
+	defer func() {
+		hub.remove(c)
+		c.conn.Close()
+	}()
+	for {
+		_, message, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var frame subscribeFrame
+		if err := json.Unmarshal(message, &frame); err != nil {
+			continue
+		}
+		if frame.Action == "subscribe" {
+			c.setSymbols(frame.Symbols)
+		}
+	}
+}
+
+// writePump writes queued Currency updates to the client as JSON messages.
+func (c *wsClient) writePump() {
+	defer c.conn.Close()
+	for currency := range c.send {
+		if err := c.conn.WriteJSON(currency); err != nil {
+			return
+		}
+	}
+}